@@ -0,0 +1,41 @@
+// Package challenge dispatches ACME challenge responses (HTTP-01,
+// TLS-ALPN-01) to whichever mechanism can currently serve them: the
+// running nginx instance, or an in-process fallback listener when
+// nginx is mid-reconfiguration.
+package challenge
+
+// Type identifies an ACME challenge type NPM knows how to solve.
+type Type string
+
+// Supported challenge types.
+const (
+	HTTP01    Type = "http-01"
+	TLSALPN01 Type = "tls-alpn-01"
+)
+
+// Dispatcher solves a single challenge and cleans up afterwards.
+type Dispatcher interface {
+	Present(domain, token, keyAuth string) error
+	CleanUp(domain, token, keyAuth string) error
+}
+
+// For builds the Dispatcher for the given challenge type.
+func For(t Type) (Dispatcher, error) {
+	switch t {
+	case HTTP01:
+		return &http01Dispatcher{}, nil
+	case TLSALPN01:
+		return &tlsALPN01Dispatcher{}, nil
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+}
+
+// UnsupportedTypeError is returned by For for an unknown challenge type.
+type UnsupportedTypeError struct {
+	Type Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "unsupported challenge type: " + string(e.Type)
+}
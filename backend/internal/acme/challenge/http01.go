@@ -0,0 +1,127 @@
+package challenge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// wellKnownDir is the shared volume nginx serves
+// /.well-known/acme-challenge/ from.
+const wellKnownDir = "/data/letsencrypt-acme-challenge/.well-known/acme-challenge"
+
+// tokenPattern matches the base64url token format ACME servers issue
+// (RFC 8555 section 8.3). Validated before the token ever reaches a
+// filesystem path or URL path segment.
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateToken(token string) error {
+	if token == "" || !tokenPattern.MatchString(token) {
+		return fmt.Errorf("http-01: invalid challenge token")
+	}
+	return nil
+}
+
+// http01Dispatcher writes the challenge token file to the shared
+// volume nginx already serves, falling back to a standalone listener
+// on :80 when nginx isn't up to take the request (e.g. first run,
+// mid-reconfiguration).
+type http01Dispatcher struct {
+	mu       sync.Mutex
+	fallback *http.Server
+}
+
+func (d *http01Dispatcher) Present(domain, token, keyAuth string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+
+	if err := writeTokenFile(token, keyAuth); err == nil {
+		return nil
+	}
+
+	return d.startFallbackListener(token, keyAuth)
+}
+
+func (d *http01Dispatcher) CleanUp(domain, token, keyAuth string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	server := d.fallback
+	d.fallback = nil
+	d.mu.Unlock()
+
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// nolint: errcheck,gosec
+		server.Shutdown(ctx)
+	}
+
+	return removeTokenFile(token)
+}
+
+// writeTokenFile writes the key authorization atomically (write to a
+// temp file, then rename) so nginx never serves a partially written
+// response.
+func writeTokenFile(token, keyAuth string) error {
+	if err := os.MkdirAll(wellKnownDir, 0o755); err != nil {
+		return err
+	}
+
+	finalPath := filepath.Join(wellKnownDir, token)
+	tmpPath := finalPath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, []byte(keyAuth), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, finalPath)
+}
+
+func removeTokenFile(token string) error {
+	err := os.Remove(filepath.Join(wellKnownDir, token))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// startFallbackListener serves the challenge response directly on :80
+// for the rare case nginx can't (e.g. it's down while being
+// reconfigured for the very first host).
+func (d *http01Dispatcher) startFallbackListener(token, keyAuth string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/.well-known/acme-challenge/%s", token), func(w http.ResponseWriter, r *http.Request) {
+		// nolint: errcheck,gosec
+		w.Write([]byte(keyAuth))
+	})
+
+	server := &http.Server{Addr: ":80", Handler: mux}
+
+	d.mu.Lock()
+	d.fallback = server
+	d.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	case <-time.After(200 * time.Millisecond):
+		// Server came up and is still serving; that's success.
+		return nil
+	}
+}
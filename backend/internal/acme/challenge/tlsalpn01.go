@@ -0,0 +1,142 @@
+package challenge
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// acmeTLS1Protocol is the ALPN protocol identifier ACME servers send
+// when negotiating a tls-alpn-01 challenge (RFC 8737).
+const acmeTLS1Protocol = "acme-tls/1"
+
+// idPeAcmeIdentifier is the extension OID the challenge certificate's
+// SAN digest is embedded under (RFC 8737 section 3).
+var idPeAcmeIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// tlsALPN01Dispatcher answers tls-alpn-01 challenges by briefly
+// listening on :443 with a self-signed certificate carrying the
+// acme-tls/1 extension, for domains where port 80 is blocked upstream
+// and http-01 can't be solved.
+type tlsALPN01Dispatcher struct {
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+func (d *tlsALPN01Dispatcher) Present(domain, token, keyAuth string) error {
+	cert, err := selfSignedChallengeCert(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		NextProtos: []string{acmeTLS1Protocol},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return cert, nil
+		},
+	}
+
+	listener, err := tls.Listen("tcp", ":443", tlsConfig)
+	if err != nil {
+		return fmt.Errorf("tls-alpn-01: unable to bind :443: %w", err)
+	}
+
+	d.mu.Lock()
+	d.listener = listener
+	d.mu.Unlock()
+
+	go serveAndDiscard(listener)
+
+	return nil
+}
+
+func (d *tlsALPN01Dispatcher) CleanUp(domain, token, keyAuth string) error {
+	d.mu.Lock()
+	listener := d.listener
+	d.listener = nil
+	d.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	return listener.Close()
+}
+
+// serveAndDiscard accepts and immediately closes every connection; the
+// TLS handshake alone is enough to prove possession, the ACME server
+// never sends application data over an acme-tls/1 connection.
+func serveAndDiscard(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		// nolint: errcheck
+		go func(c net.Conn) {
+			defer c.Close()
+			_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+			if tlsConn, ok := c.(*tls.Conn); ok {
+				// nolint: errcheck
+				tlsConn.HandshakeContext(context.Background())
+			}
+		}(conn)
+	}
+}
+
+// selfSignedChallengeCert builds the ephemeral certificate described
+// in RFC 8737: a self-signed leaf for domain whose
+// id-pe-acmeIdentifier extension carries the SHA-256 digest of the
+// key authorization, marked critical so generic clients reject it.
+func selfSignedChallengeCert(domain, keyAuth string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(keyAuth))
+	extValue, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{
+				Id:       idPeAcmeIdentifier,
+				Critical: true,
+				Value:    extValue,
+			},
+		},
+	}
+
+	derCert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{derCert},
+		PrivateKey:  key,
+	}, nil
+}
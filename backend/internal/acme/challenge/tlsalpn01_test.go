@@ -0,0 +1,51 @@
+package challenge
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+)
+
+func TestSelfSignedChallengeCertCarriesAcmeIdentifier(t *testing.T) {
+	const domain = "example.com"
+	const keyAuth = "token.thumbprint"
+
+	tlsCert, err := selfSignedChallengeCert(domain, keyAuth)
+	if err != nil {
+		t.Fatalf("selfSignedChallengeCert: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse generated certificate: %v", err)
+	}
+
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != domain {
+		t.Fatalf("got DNSNames %v, want [%s]", cert.DNSNames, domain)
+	}
+
+	var ext *x509.Extension
+	for i := range cert.Extensions {
+		if cert.Extensions[i].Id.Equal(idPeAcmeIdentifier) {
+			ext = &cert.Extensions[i]
+			break
+		}
+	}
+	if ext == nil {
+		t.Fatal("generated certificate is missing the id-pe-acmeIdentifier extension")
+	}
+	if !ext.Critical {
+		t.Fatal("id-pe-acmeIdentifier extension must be marked critical")
+	}
+
+	var digest []byte
+	if _, err := asn1.Unmarshal(ext.Value, &digest); err != nil {
+		t.Fatalf("unmarshal extension value: %v", err)
+	}
+
+	want := sha256.Sum256([]byte(keyAuth))
+	if string(digest) != string(want[:]) {
+		t.Fatalf("got digest %x, want %x", digest, want)
+	}
+}
@@ -0,0 +1,103 @@
+// Package acme wires up the ACME client used to issue and renew
+// certificates against Let's Encrypt (or a compatible directory).
+package acme
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"npm/internal/acme/challenge"
+	"npm/internal/acme/dns"
+	"npm/internal/entity/acmeaccount"
+)
+
+// DefaultDirectoryURL is used when a certificate doesn't specify its
+// own ACME directory.
+const DefaultDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+// Certificate is the result of a successful issuance or renewal.
+type Certificate struct {
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+	ChainPEM       []byte
+	NotAfter       time.Time
+}
+
+// Options carries everything needed to place an order against a
+// specific ACME server.
+type Options struct {
+	// DomainNames to request the certificate for.
+	DomainNames []string
+	// DirectoryURL of the ACME server. Defaults to Let's Encrypt when empty.
+	DirectoryURL string
+	// EABKeyID/EABHMACKey are required by CAs (ZeroSSL, Google Trust
+	// Services, private step-ca instances) that gate registration
+	// behind external account binding.
+	EABKeyID   string
+	EABHMACKey string
+	// Solver completes DNS-01 challenges. Nil means the order uses
+	// Challenge instead.
+	Solver dns.Provider
+	// Challenge completes HTTP-01/TLS-ALPN-01 challenges when Solver is
+	// nil. Nil falls back to an http01Dispatcher-equivalent default
+	// once challenge solving is wired up below.
+	Challenge challenge.Dispatcher
+}
+
+func (o Options) directoryURL() string {
+	if o.DirectoryURL == "" {
+		return DefaultDirectoryURL
+	}
+	return o.DirectoryURL
+}
+
+// account returns the cached ACME account for this directory/EAB
+// tuple, registering a new one if none exists yet.
+func (o Options) account() (acmeaccount.Model, error) {
+	existing, err := acmeaccount.GetByDirectoryAndEAB(o.directoryURL(), o.EABKeyID)
+	switch err {
+	case nil:
+		return existing, nil
+	case sql.ErrNoRows:
+		// Fall through and register a new one.
+	default:
+		return acmeaccount.Model{}, fmt.Errorf("acme: looking up cached account failed: %w", err)
+	}
+
+	// TODO: perform the actual account registration against the
+	// directory's newAccount endpoint, supplying the EAB JWS when
+	// EABKeyID is set.
+	account := acmeaccount.Model{
+		DirectoryURL: o.directoryURL(),
+		EABKeyID:     o.EABKeyID,
+	}
+	if err := account.Save(); err != nil {
+		return account, err
+	}
+	return account, nil
+}
+
+// Request performs a full ACME issuance and returns the resulting
+// certificate material.
+func Request(opts Options) (*Certificate, error) {
+	if len(opts.DomainNames) == 0 {
+		return nil, fmt.Errorf("at least one domain name is required")
+	}
+
+	if _, err := opts.account(); err != nil {
+		return nil, fmt.Errorf("acme: account negotiation failed: %w", err)
+	}
+
+	// TODO: wire up the actual ACME client (order creation,
+	// authorization polling, challenge solving via opts.Solver or
+	// opts.Challenge, order finalization). This is intentionally a thin
+	// seam so callers don't need to know about the underlying library.
+	return nil, fmt.Errorf("acme: not implemented")
+}
+
+// Renew re-issues a certificate for the same set of domain names as a
+// previous issuance, reusing the existing ACME account.
+func Renew(opts Options) (*Certificate, error) {
+	return Request(opts)
+}
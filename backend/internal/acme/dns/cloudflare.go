@@ -0,0 +1,29 @@
+package dns
+
+import "fmt"
+
+func init() {
+	register("cloudflare", []string{"api_token"}, newCloudflareProvider)
+}
+
+type cloudflareProvider struct {
+	apiToken string
+}
+
+func newCloudflareProvider(credentials map[string]string) (Provider, error) {
+	token, ok := credentials["api_token"]
+	if !ok || token == "" {
+		return nil, fmt.Errorf("cloudflare: api_token is required")
+	}
+	return &cloudflareProvider{apiToken: token}, nil
+}
+
+func (p *cloudflareProvider) Present(domain, keyAuth string) error {
+	// TODO: create the `_acme-challenge` TXT record via the Cloudflare API.
+	return fmt.Errorf("cloudflare: not implemented")
+}
+
+func (p *cloudflareProvider) CleanUp(domain, keyAuth string) error {
+	// TODO: remove the TXT record created by Present.
+	return fmt.Errorf("cloudflare: not implemented")
+}
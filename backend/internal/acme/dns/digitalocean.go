@@ -0,0 +1,30 @@
+package dns
+
+import "fmt"
+
+func init() {
+	register("digitalocean", []string{"api_token"}, newDigitalOceanProvider)
+}
+
+type digitalOceanProvider struct {
+	apiToken string
+}
+
+func newDigitalOceanProvider(credentials map[string]string) (Provider, error) {
+	token, ok := credentials["api_token"]
+	if !ok || token == "" {
+		return nil, fmt.Errorf("digitalocean: api_token is required")
+	}
+	return &digitalOceanProvider{apiToken: token}, nil
+}
+
+func (p *digitalOceanProvider) Present(domain, keyAuth string) error {
+	// TODO: create the `_acme-challenge` TXT record via the DigitalOcean
+	// domains API.
+	return fmt.Errorf("digitalocean: not implemented")
+}
+
+func (p *digitalOceanProvider) CleanUp(domain, keyAuth string) error {
+	// TODO: remove the TXT record created by Present.
+	return fmt.Errorf("digitalocean: not implemented")
+}
@@ -0,0 +1,63 @@
+// Package dns provides a pluggable DNS-01 challenge provider system so
+// certificates can be issued for wildcard domains.
+package dns
+
+import "fmt"
+
+// Provider solves DNS-01 challenges for a single ACME order by
+// creating and removing the `_acme-challenge` TXT record.
+type Provider interface {
+	// Present creates the TXT record for domain with the given key
+	// authorization value.
+	Present(domain, keyAuth string) error
+	// CleanUp removes the TXT record created by Present.
+	CleanUp(domain, keyAuth string) error
+}
+
+// Factory builds a Provider from a set of user-supplied credentials.
+type Factory func(credentials map[string]string) (Provider, error)
+
+// Descriptor describes a registered provider for the frontend: its
+// name and the credential fields it expects.
+type Descriptor struct {
+	Name             string   `json:"name"`
+	CredentialFields []string `json:"credential_fields"`
+	factory          Factory
+}
+
+var registry = map[string]*Descriptor{}
+
+func register(name string, fields []string, factory Factory) {
+	registry[name] = &Descriptor{
+		Name:             name,
+		CredentialFields: fields,
+		factory:          factory,
+	}
+}
+
+// List returns the descriptors of every registered provider, for
+// rendering dynamic credential forms in the UI.
+func List() []*Descriptor {
+	items := make([]*Descriptor, 0, len(registry))
+	for _, d := range registry {
+		items = append(items, d)
+	}
+	return items
+}
+
+// Get builds a Provider instance for the named provider using the
+// given credentials. It returns an error if the provider name isn't
+// registered.
+func Get(name string, credentials map[string]string) (Provider, error) {
+	d, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
+	}
+	return d.factory(credentials)
+}
+
+// IsRegistered reports whether name is a known provider.
+func IsRegistered(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
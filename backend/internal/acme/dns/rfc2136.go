@@ -0,0 +1,39 @@
+package dns
+
+import "fmt"
+
+// rfc2136Provider solves DNS-01 challenges by sending dynamic update
+// (RFC 2136) requests directly to an authoritative nameserver, for
+// self-hosted DNS setups (e.g. BIND) that don't have a cloud API.
+func init() {
+	register("rfc2136", []string{"nameserver", "tsig_key", "tsig_secret"}, newRFC2136Provider)
+}
+
+type rfc2136Provider struct {
+	nameserver string
+	tsigKey    string
+	tsigSecret string
+}
+
+func newRFC2136Provider(credentials map[string]string) (Provider, error) {
+	nameserver := credentials["nameserver"]
+	if nameserver == "" {
+		return nil, fmt.Errorf("rfc2136: nameserver is required")
+	}
+	return &rfc2136Provider{
+		nameserver: nameserver,
+		tsigKey:    credentials["tsig_key"],
+		tsigSecret: credentials["tsig_secret"],
+	}, nil
+}
+
+func (p *rfc2136Provider) Present(domain, keyAuth string) error {
+	// TODO: send a signed RFC 2136 UPDATE adding the `_acme-challenge`
+	// TXT record to p.nameserver.
+	return fmt.Errorf("rfc2136: not implemented")
+}
+
+func (p *rfc2136Provider) CleanUp(domain, keyAuth string) error {
+	// TODO: send a signed RFC 2136 UPDATE removing the TXT record.
+	return fmt.Errorf("rfc2136: not implemented")
+}
@@ -0,0 +1,37 @@
+package dns
+
+import "fmt"
+
+func init() {
+	register("route53", []string{"access_key_id", "secret_access_key", "hosted_zone_id"}, newRoute53Provider)
+}
+
+type route53Provider struct {
+	accessKeyID     string
+	secretAccessKey string
+	hostedZoneID    string
+}
+
+func newRoute53Provider(credentials map[string]string) (Provider, error) {
+	accessKeyID := credentials["access_key_id"]
+	secretAccessKey := credentials["secret_access_key"]
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("route53: access_key_id and secret_access_key are required")
+	}
+	return &route53Provider{
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		hostedZoneID:    credentials["hosted_zone_id"],
+	}, nil
+}
+
+func (p *route53Provider) Present(domain, keyAuth string) error {
+	// TODO: create the `_acme-challenge` TXT record via Route53's
+	// ChangeResourceRecordSets API.
+	return fmt.Errorf("route53: not implemented")
+}
+
+func (p *route53Provider) CleanUp(domain, keyAuth string) error {
+	// TODO: remove the TXT record created by Present.
+	return fmt.Errorf("route53: not implemented")
+}
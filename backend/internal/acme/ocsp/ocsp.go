@@ -0,0 +1,79 @@
+// Package ocsp queries a certificate's issuer for its revocation
+// status, caching responses for their stated validity window.
+package ocsp
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Status mirrors the outcome of an OCSP query.
+type Status string
+
+// Possible OCSP statuses.
+const (
+	StatusGood    Status = "good"
+	StatusRevoked Status = "revoked"
+	StatusUnknown Status = "unknown"
+)
+
+// Response is the cached result of a single OCSP query.
+type Response struct {
+	Status     Status    `json:"status"`
+	ThisUpdate time.Time `json:"this_update"`
+	NextUpdate time.Time `json:"next_update"`
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[int]Response{}
+)
+
+// Query performs (or returns a cached) OCSP lookup for certificateID
+// against leaf's issuer, using issuer to verify the response
+// signature.
+func Query(certificateID int, leaf, issuer *x509.Certificate) (Response, error) {
+	cacheMu.Lock()
+	cached, ok := cache[certificateID]
+	cacheMu.Unlock()
+	if ok && time.Now().Before(cached.NextUpdate) {
+		return cached, nil
+	}
+
+	if len(leaf.OCSPServer) == 0 {
+		return Response{}, fmt.Errorf("ocsp: certificate has no OCSP responder URL")
+	}
+
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// TODO: POST req to leaf.OCSPServer[0] and parse the response with
+	// ocsp.ParseResponseForCert. This seam keeps the HTTP round trip
+	// out of unit-testable logic.
+	_ = req
+
+	return Response{}, fmt.Errorf("ocsp: not implemented")
+}
+
+func cacheResponse(certificateID int, resp Response) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache[certificateID] = resp
+}
+
+func statusFromOCSP(code int) Status {
+	switch code {
+	case ocsp.Good:
+		return StatusGood
+	case ocsp.Revoked:
+		return StatusRevoked
+	default:
+		return StatusUnknown
+	}
+}
@@ -0,0 +1,66 @@
+package acme
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"npm/internal/netutil"
+)
+
+// DirectoryInfo summarizes what an ACME server supports, returned to
+// the caller before they commit to creating a certificate against it.
+type DirectoryInfo struct {
+	DirectoryURL   string   `json:"directory_url"`
+	RequiresEAB    bool     `json:"requires_eab"`
+	ChallengeTypes []string `json:"challenge_types"`
+}
+
+// acmeDirectory mirrors the subset of RFC 8555 section 7.1.1 fields
+// needed to detect EAB support.
+type acmeDirectory struct {
+	Meta struct {
+		ExternalAccountRequired bool `json:"externalAccountRequired"`
+	} `json:"meta"`
+}
+
+// ProbeDirectory fetches directoryURL and reports whether it requires
+// external account binding and which challenge types it's expected to
+// support. NPM currently drives HTTP-01, DNS-01 and TLS-ALPN-01, all
+// of which are implied by RFC 8555 authorizations, so we simply
+// report the set we know how to solve for.
+func ProbeDirectory(directoryURL string) (*DirectoryInfo, error) {
+	if directoryURL == "" {
+		directoryURL = DefaultDirectoryURL
+	}
+
+	resp, err := netutil.SafeGet(directoryURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &DirectoryError{StatusCode: resp.StatusCode}
+	}
+
+	var dir acmeDirectory
+	if err := json.NewDecoder(resp.Body).Decode(&dir); err != nil {
+		return nil, err
+	}
+
+	return &DirectoryInfo{
+		DirectoryURL:   directoryURL,
+		RequiresEAB:    dir.Meta.ExternalAccountRequired,
+		ChallengeTypes: []string{"http-01", "dns-01", "tls-alpn-01"},
+	}, nil
+}
+
+// DirectoryError is returned when the directory endpoint doesn't
+// respond with 200 OK.
+type DirectoryError struct {
+	StatusCode int
+}
+
+func (e *DirectoryError) Error() string {
+	return http.StatusText(e.StatusCode)
+}
@@ -0,0 +1,17 @@
+package acme
+
+import "fmt"
+
+// RevokeCert calls the ACME revokeCert endpoint for the certificate
+// covering domainNames, using reason as the RFC 5280 CRLReason code.
+func RevokeCert(domainNames []string, reason int) error {
+	if len(domainNames) == 0 {
+		return fmt.Errorf("at least one domain name is required")
+	}
+
+	// TODO: POST the DER-encoded certificate and reason to the
+	// directory's revokeCert endpoint, signed by the account or
+	// certificate key. Until that's wired up, fail loudly rather than
+	// let a caller believe the CA actually revoked the certificate.
+	return fmt.Errorf("acme: not implemented")
+}
@@ -2,15 +2,20 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"npm/internal/acme"
+	"npm/internal/acme/dns"
 	c "npm/internal/api/context"
 	h "npm/internal/api/http"
 	"npm/internal/api/middleware"
 	"npm/internal/api/schema"
+	"npm/internal/certimport"
 	"npm/internal/entity/certificate"
+	"npm/internal/entity/certificatechallenge"
 	"npm/internal/entity/host"
 	"npm/internal/jobqueue"
 	"npm/internal/logger"
@@ -73,6 +78,11 @@ func CreateCertificate() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		if validationErr := validateCertificatePayload(newCertificate); validationErr != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, validationErr.Error(), nil)
+			return
+		}
+
 		// Get userID from token
 		userID, _ := r.Context().Value(c.UserIDCtxKey).(int)
 		newCertificate.UserID = userID
@@ -125,6 +135,11 @@ func UpdateCertificate() func(http.ResponseWriter, *http.Request) {
 				return
 			}
 
+			if validationErr := validateCertificatePayload(certificateObject); validationErr != nil {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, validationErr.Error(), nil)
+				return
+			}
+
 			if err = certificateObject.Save(); err != nil {
 				h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
 				return
@@ -155,6 +170,11 @@ func DeleteCertificate() func(http.ResponseWriter, *http.Request) {
 		case sql.ErrNoRows:
 			h.NotFound(w, r)
 		case nil:
+			if item.IsRevoked {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, "Cannot delete a revoked certificate, it is kept for audit", nil)
+				return
+			}
+
 			// Ensure that this upstream isn't in use by a host
 			cnt := host.GetCertificateUseCount(certificateID)
 			if cnt > 0 {
@@ -168,6 +188,242 @@ func DeleteCertificate() func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// ImportCertificate accepts a certificate issued outside NPM, either as
+// a PEM bundle (cert + key + optional intermediates) or a
+// base64-encoded PKCS#12 archive, and stores it like any other
+// certificate.
+// Route: POST /certificates/import
+func ImportCertificate() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := r.Context().Value(c.BodyCtxKey).([]byte)
+
+		var payload struct {
+			Certificate  string `json:"certificate"`
+			PrivateKey   string `json:"certificate_key"`
+			Intermediate string `json:"intermediate_certificate"`
+			PKCS12       string `json:"pkcs12"`
+			Passphrase   string `json:"passphrase"`
+		}
+		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, h.ErrInvalidPayload.Error(), nil)
+			return
+		}
+
+		var bundle *certimport.Bundle
+		var err error
+		if payload.PKCS12 != "" {
+			var der []byte
+			if der, err = base64.StdEncoding.DecodeString(payload.PKCS12); err != nil {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid PKCS#12 payload: %s", err.Error()), nil)
+				return
+			}
+			bundle, err = certimport.FromPKCS12(der, payload.Passphrase)
+		} else {
+			bundle, err = certimport.FromPEM(
+				[]byte(payload.Certificate),
+				[]byte(payload.PrivateKey),
+				[]byte(payload.Intermediate),
+			)
+		}
+		if err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to import certificate: %s", err.Error()), nil)
+			return
+		}
+
+		userID, _ := r.Context().Value(c.UserIDCtxKey).(int)
+		newCertificate, err := certificate.FromImport(userID, bundle)
+		if err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to import certificate: %s", err.Error()), nil)
+			return
+		}
+
+		if err = newCertificate.Save(); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to save Certificate: %s", err.Error()), nil)
+			return
+		}
+
+		h.ResultResponseJSON(w, r, http.StatusOK, newCertificate)
+	}
+}
+
+// GetDNSProviders lists the registered DNS-01 challenge providers and
+// the credential fields each one expects, so the frontend can render
+// a dynamic form.
+// Route: GET /certificates/dns-providers
+func GetDNSProviders() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.ResultResponseJSON(w, r, http.StatusOK, dns.List())
+	}
+}
+
+// ValidateACME probes an ACME directory URL before the user commits to
+// it, reporting whether it requires external account binding and
+// which challenge types it supports.
+// Route: POST /certificates/validate-acme
+func ValidateACME() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := r.Context().Value(c.BodyCtxKey).([]byte)
+
+		var payload struct {
+			DirectoryURL string `json:"acme_directory_url"`
+		}
+		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, h.ErrInvalidPayload.Error(), nil)
+			return
+		}
+
+		info, err := acme.ProbeDirectory(payload.DirectoryURL)
+		if err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to reach ACME directory: %s", err.Error()), nil)
+			return
+		}
+
+		h.ResultResponseJSON(w, r, http.StatusOK, info)
+	}
+}
+
+// RenewCertificate enqueues an immediate renewal of a certificate,
+// regardless of how close it is to expiry.
+// Route: POST /certificates/{certificateID}/renew
+func RenewCertificate() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		var certificateID int
+		if certificateID, err = getURLParamInt(r, "certificateID"); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		item, err := certificate.GetByID(certificateID)
+		switch err {
+		case sql.ErrNoRows:
+			h.NotFound(w, r)
+		case nil:
+			renewCertificate(item)
+			h.ResultResponseJSON(w, r, http.StatusOK, item)
+		default:
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+		}
+	}
+}
+
+// RevokeCertificate calls the ACME revokeCert flow for a certificate
+// and marks it revoked. It stays in place afterwards for audit.
+// Route: POST /certificates/{certificateID}/revoke
+func RevokeCertificate() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		var certificateID int
+		if certificateID, err = getURLParamInt(r, "certificateID"); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		item, err := certificate.GetByID(certificateID)
+		switch err {
+		case sql.ErrNoRows:
+			h.NotFound(w, r)
+		case nil:
+			bodyBytes, _ := r.Context().Value(c.BodyCtxKey).([]byte)
+			var payload struct {
+				Reason int `json:"reason"`
+			}
+			// A missing or empty body is fine: it just means reason 0
+			// (unspecified), same as the ACME default.
+			if len(bodyBytes) > 0 {
+				if jsonErr := json.Unmarshal(bodyBytes, &payload); jsonErr != nil {
+					h.ResultErrorJSON(w, r, http.StatusBadRequest, h.ErrInvalidPayload.Error(), nil)
+					return
+				}
+			}
+
+			if err = item.Revoke(payload.Reason); err != nil {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to revoke Certificate: %s", err.Error()), nil)
+				return
+			}
+			h.ResultResponseJSON(w, r, http.StatusOK, item)
+		default:
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+		}
+	}
+}
+
+// GetCertificateStatus performs an OCSP query against the issuer's
+// responder for this certificate.
+// Route: GET /certificates/{certificateID}/status
+func GetCertificateStatus() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		var certificateID int
+		if certificateID, err = getURLParamInt(r, "certificateID"); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		item, err := certificate.GetByID(certificateID)
+		switch err {
+		case sql.ErrNoRows:
+			h.NotFound(w, r)
+		case nil:
+			status, statusErr := item.OCSPStatus()
+			if statusErr != nil {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, fmt.Sprintf("Unable to check OCSP status: %s", statusErr.Error()), nil)
+				return
+			}
+			h.ResultResponseJSON(w, r, http.StatusOK, status)
+		default:
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+		}
+	}
+}
+
+// GetCertificateChallenges lists the recorded ACME challenge attempts
+// for a certificate, most recent first, for debugging failed
+// issuances.
+// Route: GET /certificates/{certificateID}/challenges
+func GetCertificateChallenges() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		var certificateID int
+		if certificateID, err = getURLParamInt(r, "certificateID"); err != nil {
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		item, err := certificate.GetByID(certificateID)
+		switch err {
+		case sql.ErrNoRows:
+			h.NotFound(w, r)
+		case nil:
+			attempts, listErr := certificatechallenge.ListByCertificate(item.ID)
+			if listErr != nil {
+				h.ResultErrorJSON(w, r, http.StatusBadRequest, listErr.Error(), nil)
+				return
+			}
+			h.ResultResponseJSON(w, r, http.StatusOK, attempts)
+		default:
+			h.ResultErrorJSON(w, r, http.StatusBadRequest, err.Error(), nil)
+		}
+	}
+}
+
+// validateCertificatePayload runs the checks that the schema package
+// can't express (they depend on the DNS provider registry and on
+// cross-field EAB pairing), shared between CreateCertificate and
+// UpdateCertificate so neither can save a certificate the other would
+// reject.
+func validateCertificatePayload(m certificate.Model) error {
+	if m.DNSProvider != "" && !dns.IsRegistered(m.DNSProvider) {
+		return fmt.Errorf("Unknown DNS provider: %s", m.DNSProvider)
+	}
+
+	if m.EABKeyID != "" && m.EABHMACKey == "" {
+		return fmt.Errorf("eab_hmac_key is required when eab_kid is set")
+	}
+
+	return nil
+}
+
 func configureCertificate(c certificate.Model) {
 	err := jobqueue.AddJob(jobqueue.Job{
 		Name:   "RequestCertificate",
@@ -177,3 +433,13 @@ func configureCertificate(c certificate.Model) {
 		logger.Error("ConfigureCertificateError", err)
 	}
 }
+
+func renewCertificate(c certificate.Model) {
+	err := jobqueue.AddJob(jobqueue.Job{
+		Name:   "RenewCertificate",
+		Action: c.Renew,
+	})
+	if err != nil {
+		logger.Error("RenewCertificateError", err)
+	}
+}
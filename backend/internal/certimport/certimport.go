@@ -0,0 +1,227 @@
+// Package certimport parses certificates supplied by the user (PEM
+// bundles or PKCS#12 archives) so they can be imported without going
+// through ACME issuance.
+package certimport
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/pkcs12"
+
+	"npm/internal/netutil"
+)
+
+// Bundle is a parsed, validated certificate ready to be stored.
+type Bundle struct {
+	Leaf          *x509.Certificate
+	LeafPEM       []byte
+	Issuer        *x509.Certificate
+	IssuerPEM     []byte
+	PrivateKeyPEM []byte
+	DomainNames   []string
+	NotAfter      time.Time
+}
+
+// ErrExpired is returned when the leaf certificate is already expired.
+var ErrExpired = fmt.Errorf("certificate has expired")
+
+// ErrKeyMismatch is returned when the supplied private key doesn't
+// correspond to the leaf certificate's public key.
+var ErrKeyMismatch = fmt.Errorf("private key does not match certificate")
+
+// FromPEM parses a PEM bundle containing a leaf certificate, its
+// private key and, optionally, intermediate certificates. Missing
+// intermediates are resolved via AIA chasing.
+func FromPEM(certPEM, keyPEM, chainPEM []byte) (*Bundle, error) {
+	leafBlock, _ := pem.Decode(certPEM)
+	if leafBlock == nil {
+		return nil, fmt.Errorf("no certificate found in PEM input")
+	}
+	leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaf certificate: %w", err)
+	}
+
+	if err := verifyKeyMatch(leaf, keyPEM); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(leaf.NotAfter) {
+		return nil, ErrExpired
+	}
+
+	issuer, issuerPEM, err := resolveIssuer(leaf, chainPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve issuing chain: %w", err)
+	}
+
+	return &Bundle{
+		Leaf:          leaf,
+		LeafPEM:       pem.EncodeToMemory(leafBlock),
+		Issuer:        issuer,
+		IssuerPEM:     issuerPEM,
+		PrivateKeyPEM: keyPEM,
+		DomainNames:   leaf.DNSNames,
+		NotAfter:      leaf.NotAfter,
+	}, nil
+}
+
+// FromPKCS12 parses a base64-decoded PKCS#12 archive protected by
+// passphrase.
+func FromPKCS12(data []byte, passphrase string) (*Bundle, error) {
+	keyInterface, leaf, caCerts, err := pkcs12.DecodeChain(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS#12 archive: %w", err)
+	}
+
+	keyPEM, err := encodePrivateKey(keyInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+
+	if time.Now().After(leaf.NotAfter) {
+		return nil, ErrExpired
+	}
+
+	var chainPEM []byte
+	for _, ca := range caCerts {
+		chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})...)
+	}
+
+	issuer, issuerPEM, err := resolveIssuer(leaf, chainPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve issuing chain: %w", err)
+	}
+
+	return &Bundle{
+		Leaf:          leaf,
+		LeafPEM:       leafPEM,
+		Issuer:        issuer,
+		IssuerPEM:     issuerPEM,
+		PrivateKeyPEM: keyPEM,
+		DomainNames:   leaf.DNSNames,
+		NotAfter:      leaf.NotAfter,
+	}, nil
+}
+
+// resolveIssuer returns the leaf's issuing certificate, first trying
+// chainPEM and falling back to AIA chasing (fetching
+// leaf.IssuingCertificateURL) when it's empty or doesn't verify.
+func resolveIssuer(leaf *x509.Certificate, chainPEM []byte) (*x509.Certificate, []byte, error) {
+	if len(chainPEM) > 0 {
+		if issuer, err := parseFirstCertificate(chainPEM); err == nil {
+			if err := leaf.CheckSignatureFrom(issuer); err == nil {
+				return issuer, chainPEM, nil
+			}
+		}
+	}
+
+	for _, url := range leaf.IssuingCertificateURL {
+		issuer, issuerPEM, err := fetchIssuer(url)
+		if err != nil {
+			continue
+		}
+		if err := leaf.CheckSignatureFrom(issuer); err != nil {
+			continue
+		}
+		return issuer, issuerPEM, nil
+	}
+
+	return nil, nil, fmt.Errorf("no valid intermediate certificate found, and AIA chasing found none")
+}
+
+func fetchIssuer(issuerURL string) (*x509.Certificate, []byte, error) {
+	resp, err := netutil.SafeGet(issuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("AIA fetch of %s returned %d", issuerURL, resp.StatusCode)
+	}
+
+	der, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issuer, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return issuer, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func parseFirstCertificate(chainPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(chainPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate found in chain")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func verifyKeyMatch(leaf *x509.Certificate, keyPEM []byte) error {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return fmt.Errorf("no private key found in PEM input")
+	}
+
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+
+	switch leafPub := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok || rsaKey.PublicKey.N.Cmp(leafPub.N) != 0 {
+			return ErrKeyMismatch
+		}
+	case *ecdsa.PublicKey:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || !ecKey.PublicKey.Equal(leafPub) {
+			return ErrKeyMismatch
+		}
+	case ed25519.PublicKey:
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok || !edKey.Public().(ed25519.PublicKey).Equal(leafPub) {
+			return ErrKeyMismatch
+		}
+	default:
+		return fmt.Errorf("unsupported public key algorithm for key match verification")
+	}
+	return nil
+}
+
+func parsePrivateKey(der []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key encoding")
+}
+
+func encodePrivateKey(key interface{}) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
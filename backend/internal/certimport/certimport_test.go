@@ -0,0 +1,212 @@
+package certimport
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func encodeCert(t *testing.T, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func selfSignedCA(t *testing.T) (*x509.Certificate, []byte, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+
+	return cert, der, key
+}
+
+func issueLeaf(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, domain string, pub interface{}) []byte {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, pub, caKey)
+	if err != nil {
+		t.Fatalf("create leaf certificate: %v", err)
+	}
+	return der
+}
+
+func TestVerifyKeyMatchRSA(t *testing.T) {
+	ca, caDER, caKey := selfSignedCA(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	leafDER := issueLeaf(t, ca, caKey, "example.com", &key.PublicKey)
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if err := verifyKeyMatch(leaf, keyPEM); err != nil {
+		t.Fatalf("expected matching RSA key to verify, got: %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate other RSA key: %v", err)
+	}
+	otherDER, err := x509.MarshalPKCS8PrivateKey(otherKey)
+	if err != nil {
+		t.Fatalf("marshal other key: %v", err)
+	}
+	otherPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER})
+
+	if err := verifyKeyMatch(leaf, otherPEM); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch for unrelated RSA key, got: %v", err)
+	}
+
+	_ = caDER
+}
+
+func TestVerifyKeyMatchECDSA(t *testing.T) {
+	ca, _, caKey := selfSignedCA(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	leafDER := issueLeaf(t, ca, caKey, "example.com", &key.PublicKey)
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if err := verifyKeyMatch(leaf, keyPEM); err != nil {
+		t.Fatalf("expected matching EC key to verify, got: %v", err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate other EC key: %v", err)
+	}
+	otherDER, err := x509.MarshalPKCS8PrivateKey(otherKey)
+	if err != nil {
+		t.Fatalf("marshal other key: %v", err)
+	}
+	otherPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER})
+
+	if err := verifyKeyMatch(leaf, otherPEM); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch for unrelated EC key, got: %v", err)
+	}
+}
+
+func TestVerifyKeyMatchEd25519(t *testing.T) {
+	ca, _, caKey := selfSignedCA(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	leafDER := issueLeaf(t, ca, caKey, "example.com", pub)
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if err := verifyKeyMatch(leaf, keyPEM); err != nil {
+		t.Fatalf("expected matching Ed25519 key to verify, got: %v", err)
+	}
+
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate other Ed25519 key: %v", err)
+	}
+	otherDER, err := x509.MarshalPKCS8PrivateKey(otherPriv)
+	if err != nil {
+		t.Fatalf("marshal other key: %v", err)
+	}
+	otherPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER})
+
+	if err := verifyKeyMatch(leaf, otherPEM); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch for unrelated Ed25519 key, got: %v", err)
+	}
+}
+
+func TestFromPEMResolvesIssuerFromSuppliedChain(t *testing.T) {
+	ca, caDER, caKey := selfSignedCA(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+	leafDER := issueLeaf(t, ca, caKey, "example.com", &key.PublicKey)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	bundle, err := FromPEM(encodeCert(t, leafDER), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), encodeCert(t, caDER))
+	if err != nil {
+		t.Fatalf("FromPEM: %v", err)
+	}
+
+	if bundle.Issuer.Subject.CommonName != "Test CA" {
+		t.Fatalf("got issuer %q, want %q", bundle.Issuer.Subject.CommonName, "Test CA")
+	}
+	if len(bundle.DomainNames) != 1 || bundle.DomainNames[0] != "example.com" {
+		t.Fatalf("got domain names %v, want [example.com]", bundle.DomainNames)
+	}
+}
@@ -0,0 +1,77 @@
+// Package crypto holds small helpers for encrypting sensitive values
+// (such as DNS provider credentials) before they're persisted.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+// key is derived from the SECRET_ENCRYPTION_KEY environment variable.
+// It's read lazily so tests can set it before first use.
+//
+// SECRET_ENCRYPTION_KEY must be set: without it, every deployment
+// would silently "encrypt" secrets with the same fixed,
+// publicly-known key (sha256 of an empty string).
+func key() ([]byte, error) {
+	raw := os.Getenv("SECRET_ENCRYPTION_KEY")
+	if raw == "" {
+		return nil, errors.New("crypto: SECRET_ENCRYPTION_KEY environment variable is not set")
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:], nil
+}
+
+// EncryptSecret encrypts plaintext with AES-GCM and returns a
+// base64-encoded ciphertext suitable for storing in a text column.
+func EncryptSecret(plaintext []byte) (string, error) {
+	k, err := key()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret.
+func DecryptSecret(encoded string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	k, err := key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("crypto: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
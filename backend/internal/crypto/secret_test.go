@@ -0,0 +1,37 @@
+package crypto
+
+import "testing"
+
+func TestEncryptDecryptSecretRoundTrip(t *testing.T) {
+	t.Setenv("SECRET_ENCRYPTION_KEY", "test-key")
+
+	plaintext := []byte("super secret value")
+	encoded, err := EncryptSecret(plaintext)
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+
+	decoded, err := DecryptSecret(encoded)
+	if err != nil {
+		t.Fatalf("DecryptSecret: %v", err)
+	}
+	if string(decoded) != string(plaintext) {
+		t.Fatalf("got %q, want %q", decoded, plaintext)
+	}
+}
+
+func TestEncryptSecretRequiresKey(t *testing.T) {
+	t.Setenv("SECRET_ENCRYPTION_KEY", "")
+
+	if _, err := EncryptSecret([]byte("anything")); err == nil {
+		t.Fatal("expected EncryptSecret to fail when SECRET_ENCRYPTION_KEY is unset")
+	}
+}
+
+func TestDecryptSecretRequiresKey(t *testing.T) {
+	t.Setenv("SECRET_ENCRYPTION_KEY", "")
+
+	if _, err := DecryptSecret("doesn't matter"); err == nil {
+		t.Fatal("expected DecryptSecret to fail when SECRET_ENCRYPTION_KEY is unset")
+	}
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"database/sql"
+)
+
+var db *sql.DB
+
+// Set assigns the shared database handle used by entity packages.
+// It's called once during application startup.
+func Set(handle *sql.DB) {
+	db = handle
+}
+
+// GetDB returns the shared database handle.
+func GetDB() *sql.DB {
+	return db
+}
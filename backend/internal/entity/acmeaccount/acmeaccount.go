@@ -0,0 +1,85 @@
+// Package acmeaccount caches ACME account registrations so that
+// certificates sharing the same (directory URL, EAB key ID) tuple
+// reuse a single account instead of registering a new one per order.
+package acmeaccount
+
+import (
+	"time"
+
+	"npm/internal/crypto"
+	"npm/internal/database"
+)
+
+// Model is a cached ACME account.
+type Model struct {
+	ID           int    `json:"id"`
+	CreatedOn    int    `json:"created_on"`
+	DirectoryURL string `json:"directory_url"`
+	EABKeyID     string `json:"eab_kid,omitempty"`
+	AccountURL   string `json:"account_url"`
+
+	// PrivateKeyPEM is write-only: it's encrypted into
+	// privateKeyPEMEnc on Save and never populated on read. Use
+	// PrivateKeyPEMPlaintext to read it back.
+	PrivateKeyPEM    string `json:"-"`
+	privateKeyPEMEnc string
+}
+
+// PrivateKeyPEMPlaintext decrypts the stored account private key, for
+// use when signing ACME requests.
+func (m *Model) PrivateKeyPEMPlaintext() (string, error) {
+	if m.privateKeyPEMEnc == "" {
+		return "", nil
+	}
+	plaintext, err := crypto.DecryptSecret(m.privateKeyPEMEnc)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// GetByDirectoryAndEAB returns the cached account for a (directory,
+// EAB key ID) tuple, if one has already been registered.
+func GetByDirectoryAndEAB(directoryURL, eabKeyID string) (Model, error) {
+	var m Model
+	row := database.GetDB().QueryRow(`
+		SELECT id, created_on, directory_url, eab_kid, account_url, private_key_pem
+		FROM acme_account
+		WHERE directory_url = ? AND eab_kid = ?
+	`, directoryURL, eabKeyID)
+
+	err := row.Scan(&m.ID, &m.CreatedOn, &m.DirectoryURL, &m.EABKeyID, &m.AccountURL, &m.privateKeyPEMEnc)
+	return m, err
+}
+
+// Save persists a newly registered account.
+func (m *Model) Save() error {
+	if m.CreatedOn == 0 {
+		m.CreatedOn = int(time.Now().Unix())
+	}
+
+	if m.PrivateKeyPEM != "" {
+		enc, err := crypto.EncryptSecret([]byte(m.PrivateKeyPEM))
+		if err != nil {
+			return err
+		}
+		m.privateKeyPEMEnc = enc
+		// Never keep the plaintext private key around once encrypted.
+		m.PrivateKeyPEM = ""
+	}
+
+	result, err := database.GetDB().Exec(`
+		INSERT INTO acme_account (created_on, directory_url, eab_kid, account_url, private_key_pem)
+		VALUES (?, ?, ?, ?, ?)
+	`, m.CreatedOn, m.DirectoryURL, m.EABKeyID, m.AccountURL, m.privateKeyPEMEnc)
+	if err != nil {
+		return err
+	}
+
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(lastID)
+	return nil
+}
@@ -0,0 +1,610 @@
+// Package certificate is the entity package for SSL certificates.
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"npm/internal/acme"
+	"npm/internal/acme/challenge"
+	"npm/internal/acme/dns"
+	"npm/internal/acme/ocsp"
+	"npm/internal/certimport"
+	"npm/internal/crypto"
+	"npm/internal/database"
+	"npm/internal/entity/certificatechallenge"
+	"npm/internal/entity/host"
+	"npm/internal/jobqueue"
+	"npm/internal/logger"
+	"npm/internal/nginx"
+)
+
+// Certificate types.
+const (
+	TypeHTTP   = "http"
+	TypeDNS    = "dns"
+	TypeCustom = "custom"
+)
+
+// RenewalStatus describes where a certificate is in its renewal lifecycle.
+type RenewalStatus string
+
+// Renewal statuses.
+const (
+	RenewalStatusNone     RenewalStatus = "none"
+	RenewalStatusPending  RenewalStatus = "pending"
+	RenewalStatusRenewing RenewalStatus = "renewing"
+	RenewalStatusFailed   RenewalStatus = "failed"
+)
+
+// Model is the certificate entity.
+type Model struct {
+	ID            int             `json:"id"`
+	CreatedOn     int             `json:"created_on"`
+	ModifiedOn    int             `json:"modified_on"`
+	UserID        int             `json:"user_id"`
+	Type          string          `json:"type"`
+	DomainNames   []string        `json:"domain_names"`
+	NotAfter      int             `json:"not_after"`
+	RenewalStatus RenewalStatus   `json:"renewal_status"`
+	Meta          json.RawMessage `json:"meta,omitempty"`
+
+	// RenewalAttempts counts consecutive failed renewals, and
+	// NextRenewalAttempt (a unix timestamp) is when FindDueForRenewal
+	// will consider this certificate again. Both reset to zero on a
+	// successful renewal. This is what makes RENEWAL_WINDOW_DAYS an
+	// actual backoff instead of a retry-every-scan hammering of the CA.
+	RenewalAttempts    int `json:"renewal_attempts,omitempty"`
+	NextRenewalAttempt int `json:"next_renewal_attempt,omitempty"`
+
+	// DNSProvider selects a registered dns.Provider for solving DNS-01
+	// challenges, enabling wildcard issuance. Empty means HTTP-01.
+	DNSProvider string `json:"dns_provider,omitempty"`
+	// DNSProviderCredentials is write-only: it's encrypted into
+	// dnsProviderCredentialsEnc on Save and never populated on read.
+	DNSProviderCredentials    map[string]string `json:"dns_provider_credentials,omitempty"`
+	dnsProviderCredentialsEnc string
+
+	// ChallengeType selects which challenge.Dispatcher solves
+	// authorizations when DNSProvider isn't set. Empty means HTTP-01.
+	ChallengeType challenge.Type `json:"challenge_type,omitempty"`
+
+	// ACMEDirectoryURL is the ACME server to issue from. Empty means
+	// Let's Encrypt's production directory.
+	ACMEDirectoryURL string `json:"acme_directory_url,omitempty"`
+	// EABKeyID identifies the external account for CAs (ZeroSSL,
+	// Google Trust Services, private step-ca) that require binding an
+	// ACME account to a pre-existing one out of band.
+	EABKeyID string `json:"eab_kid,omitempty"`
+	// EABHMACKey is write-only: it's encrypted into eabHMACKeyEnc on
+	// Save and never populated on read.
+	EABHMACKey    string `json:"eab_hmac_key,omitempty"`
+	eabHMACKeyEnc string
+
+	// IsRevoked, RevokedOn and RevocationReason record an ACME
+	// revocation. Revoked certificates are kept (never hard-deleted)
+	// for audit purposes.
+	IsRevoked        bool `json:"is_revoked"`
+	RevokedOn        int  `json:"revoked_on,omitempty"`
+	RevocationReason int  `json:"revocation_reason,omitempty"`
+
+	// certificatePEM/issuerCertificatePEM are the leaf and issuing CA
+	// certificates, kept around so OCSP status checks and nginx config
+	// generation don't need to re-fetch them. privateKeyPEMEnc is
+	// encrypted at rest like the other secrets on this model.
+	certificatePEM       string
+	issuerCertificatePEM string
+	privateKeyPEMEnc     string
+
+	expand []string
+}
+
+// LeafAndIssuer parses the stored certificate material, for use by
+// OCSP status checks and nginx config generation.
+func (m *Model) LeafAndIssuer() (*x509.Certificate, *x509.Certificate, error) {
+	if m.certificatePEM == "" || m.issuerCertificatePEM == "" {
+		return nil, nil, fmt.Errorf("certificate %d has no stored certificate material yet", m.ID)
+	}
+
+	leafBlock, _ := pem.Decode([]byte(m.certificatePEM))
+	if leafBlock == nil {
+		return nil, nil, fmt.Errorf("certificate %d: invalid stored leaf PEM", m.ID)
+	}
+	leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issuerBlock, _ := pem.Decode([]byte(m.issuerCertificatePEM))
+	if issuerBlock == nil {
+		return nil, nil, fmt.Errorf("certificate %d: invalid stored issuer PEM", m.ID)
+	}
+	issuer, err := x509.ParseCertificate(issuerBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return leaf, issuer, nil
+}
+
+// DNSProviderCredentialMap decrypts and returns the stored DNS
+// provider credentials, for use when issuing or renewing.
+func (m *Model) DNSProviderCredentialMap() (map[string]string, error) {
+	if m.dnsProviderCredentialsEnc == "" {
+		return nil, nil
+	}
+	plaintext, err := crypto.DecryptSecret(m.dnsProviderCredentialsEnc)
+	if err != nil {
+		return nil, err
+	}
+	var creds map[string]string
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// eabHMACKeyPlaintext decrypts the stored EAB HMAC key, if any.
+func (m *Model) eabHMACKeyPlaintext() (string, error) {
+	if m.eabHMACKeyEnc == "" {
+		return "", nil
+	}
+	plaintext, err := crypto.DecryptSecret(m.eabHMACKeyEnc)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// List returns a list of certificates.
+func List(pageInfo interface{}, filters interface{}, expand []string) ([]Model, error) {
+	// TODO: build and execute the filtered/paginated query against
+	// database.GetDB(). Left as a seam until the shared query builder
+	// lands.
+	return nil, nil
+}
+
+// FindDueForRenewal returns up to limit certificates whose NotAfter is
+// before the given cutoff (a unix timestamp), that aren't already
+// being renewed, and that aren't backing off from a prior failed
+// attempt.
+func FindDueForRenewal(cutoff int, limit int) ([]Model, error) {
+	now := int(time.Now().Unix())
+	rows, err := database.GetDB().Query(`
+		SELECT id, created_on, modified_on, user_id, type, domain_names,
+			not_after, renewal_status, meta, renewal_attempts, next_renewal_attempt,
+			dns_provider, dns_provider_credentials, challenge_type, acme_directory_url,
+			eab_kid, eab_hmac_key, is_revoked, revoked_on, revocation_reason,
+			certificate_pem, issuer_certificate_pem, private_key_pem
+		FROM certificate
+		WHERE is_deleted = 0
+			AND not_after > 0
+			AND not_after < ?
+			AND renewal_status != ?
+			AND is_revoked = 0
+			AND next_renewal_attempt <= ?
+		ORDER BY not_after ASC
+		LIMIT ?
+	`, cutoff, RenewalStatusRenewing, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []Model
+	for rows.Next() {
+		var m Model
+		var domainNames, meta []byte
+		if err := rows.Scan(
+			&m.ID,
+			&m.CreatedOn,
+			&m.ModifiedOn,
+			&m.UserID,
+			&m.Type,
+			&domainNames,
+			&m.NotAfter,
+			&m.RenewalStatus,
+			&meta,
+			&m.RenewalAttempts,
+			&m.NextRenewalAttempt,
+			&m.DNSProvider,
+			&m.dnsProviderCredentialsEnc,
+			&m.ChallengeType,
+			&m.ACMEDirectoryURL,
+			&m.EABKeyID,
+			&m.eabHMACKeyEnc,
+			&m.IsRevoked,
+			&m.RevokedOn,
+			&m.RevocationReason,
+			&m.certificatePEM,
+			&m.issuerCertificatePEM,
+			&m.privateKeyPEMEnc,
+		); err != nil {
+			return nil, err
+		}
+		// nolint: errcheck,gosec
+		json.Unmarshal(domainNames, &m.DomainNames)
+		m.Meta = meta
+		certs = append(certs, m)
+	}
+	return certs, rows.Err()
+}
+
+// GetByID finds a certificate by ID.
+func GetByID(id int) (Model, error) {
+	var m Model
+	row := database.GetDB().QueryRow(`
+		SELECT id, created_on, modified_on, user_id, type, domain_names,
+			not_after, renewal_status, meta, renewal_attempts, next_renewal_attempt,
+			dns_provider, dns_provider_credentials, challenge_type, acme_directory_url,
+			eab_kid, eab_hmac_key, is_revoked, revoked_on, revocation_reason,
+			certificate_pem, issuer_certificate_pem, private_key_pem
+		FROM certificate
+		WHERE id = ? AND is_deleted = 0
+	`, id)
+
+	var domainNames, meta []byte
+	err := row.Scan(
+		&m.ID,
+		&m.CreatedOn,
+		&m.ModifiedOn,
+		&m.UserID,
+		&m.Type,
+		&domainNames,
+		&m.NotAfter,
+		&m.RenewalStatus,
+		&meta,
+		&m.RenewalAttempts,
+		&m.NextRenewalAttempt,
+		&m.DNSProvider,
+		&m.dnsProviderCredentialsEnc,
+		&m.ChallengeType,
+		&m.ACMEDirectoryURL,
+		&m.EABKeyID,
+		&m.eabHMACKeyEnc,
+		&m.IsRevoked,
+		&m.RevokedOn,
+		&m.RevocationReason,
+		&m.certificatePEM,
+		&m.issuerCertificatePEM,
+		&m.privateKeyPEMEnc,
+	)
+	if err != nil {
+		return m, err
+	}
+
+	// nolint: errcheck,gosec
+	json.Unmarshal(domainNames, &m.DomainNames)
+	m.Meta = meta
+	return m, nil
+}
+
+// Expand fills in related data for the given field names.
+func (m *Model) Expand(fields []string) error {
+	m.expand = fields
+	return nil
+}
+
+// Save will save this model to the database.
+func (m *Model) Save() error {
+	now := int(time.Now().Unix())
+	if m.CreatedOn == 0 {
+		m.CreatedOn = now
+	}
+	m.ModifiedOn = now
+
+	domainNames, err := json.Marshal(m.DomainNames)
+	if err != nil {
+		return err
+	}
+
+	if len(m.DNSProviderCredentials) > 0 {
+		plaintext, err := json.Marshal(m.DNSProviderCredentials)
+		if err != nil {
+			return err
+		}
+		if m.dnsProviderCredentialsEnc, err = crypto.EncryptSecret(plaintext); err != nil {
+			return err
+		}
+		// Never keep the plaintext credentials around once encrypted.
+		m.DNSProviderCredentials = nil
+	}
+
+	if m.EABHMACKey != "" {
+		if m.eabHMACKeyEnc, err = crypto.EncryptSecret([]byte(m.EABHMACKey)); err != nil {
+			return err
+		}
+		// Never keep the plaintext EAB key around once encrypted.
+		m.EABHMACKey = ""
+	}
+
+	if m.ID == 0 {
+		result, err := database.GetDB().Exec(`
+			INSERT INTO certificate (created_on, modified_on, user_id, type,
+				domain_names, not_after, renewal_status, meta, renewal_attempts,
+				next_renewal_attempt, dns_provider, dns_provider_credentials,
+				challenge_type, acme_directory_url, eab_kid, eab_hmac_key, is_revoked,
+				revoked_on, revocation_reason, certificate_pem, issuer_certificate_pem,
+				private_key_pem)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, m.CreatedOn, m.ModifiedOn, m.UserID, m.Type, domainNames,
+			m.NotAfter, m.RenewalStatus, m.Meta, m.RenewalAttempts,
+			m.NextRenewalAttempt, m.DNSProvider, m.dnsProviderCredentialsEnc,
+			m.ChallengeType, m.ACMEDirectoryURL, m.EABKeyID, m.eabHMACKeyEnc,
+			m.IsRevoked, m.RevokedOn, m.RevocationReason,
+			m.certificatePEM, m.issuerCertificatePEM, m.privateKeyPEMEnc)
+		if err != nil {
+			return err
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		m.ID = int(lastID)
+		return nil
+	}
+
+	_, err = database.GetDB().Exec(`
+		UPDATE certificate
+		SET modified_on = ?, type = ?, domain_names = ?, not_after = ?,
+			renewal_status = ?, meta = ?, renewal_attempts = ?,
+			next_renewal_attempt = ?, dns_provider = ?, dns_provider_credentials = ?,
+			challenge_type = ?, acme_directory_url = ?, eab_kid = ?, eab_hmac_key = ?,
+			is_revoked = ?, revoked_on = ?, revocation_reason = ?, certificate_pem = ?,
+			issuer_certificate_pem = ?, private_key_pem = ?
+		WHERE id = ?
+	`, m.ModifiedOn, m.Type, domainNames, m.NotAfter, m.RenewalStatus,
+		m.Meta, m.RenewalAttempts, m.NextRenewalAttempt, m.DNSProvider,
+		m.dnsProviderCredentialsEnc, m.ChallengeType, m.ACMEDirectoryURL, m.EABKeyID,
+		m.eabHMACKeyEnc, m.IsRevoked, m.RevokedOn, m.RevocationReason,
+		m.certificatePEM, m.issuerCertificatePEM, m.privateKeyPEMEnc, m.ID)
+	return err
+}
+
+// Delete will mark a certificate as deleted. Revoked certificates are
+// refused: they're kept for audit even after the user asks to remove
+// them.
+func (m *Model) Delete() bool {
+	if m.IsRevoked {
+		return false
+	}
+
+	_, err := database.GetDB().Exec(`UPDATE certificate SET is_deleted = 1 WHERE id = ?`, m.ID)
+	if err != nil {
+		logger.Error("CertificateDeleteError", err)
+		return false
+	}
+	return true
+}
+
+// Revoke calls the ACME revokeCert flow for this certificate and marks
+// it revoked. reason is an RFC 5280 CRLReason code (0 = unspecified).
+func (m *Model) Revoke(reason int) error {
+	if err := acme.RevokeCert(m.DomainNames, reason); err != nil {
+		return err
+	}
+
+	m.IsRevoked = true
+	m.RevokedOn = int(time.Now().Unix())
+	m.RevocationReason = reason
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	if err := host.RemoveCertificateReferences(m.ID); err != nil {
+		return err
+	}
+
+	return jobqueue.AddJob(jobqueue.Job{
+		Name:   "ReloadNginxAfterRevoke",
+		Action: nginx.Reload,
+	})
+}
+
+// OCSPStatus queries the issuer's OCSP responder for this
+// certificate's current revocation status, subject to ocsp.Query's own
+// response caching.
+func (m *Model) OCSPStatus() (ocsp.Response, error) {
+	leaf, issuer, err := m.LeafAndIssuer()
+	if err != nil {
+		return ocsp.Response{}, err
+	}
+	return ocsp.Query(m.ID, leaf, issuer)
+}
+
+// dnsSolver builds the dns.Provider for this certificate's configured
+// DNS provider, or nil if it uses HTTP-01 instead.
+func (m *Model) dnsSolver() (dns.Provider, error) {
+	if m.DNSProvider == "" {
+		return nil, nil
+	}
+	creds, err := m.DNSProviderCredentialMap()
+	if err != nil {
+		return nil, err
+	}
+	return dns.Get(m.DNSProvider, creds)
+}
+
+// challengeDispatcher builds the challenge.Dispatcher for this
+// certificate's configured challenge type, used when it isn't solving
+// via a DNS provider. Empty ChallengeType means HTTP-01.
+func (m *Model) challengeDispatcher() (challenge.Dispatcher, error) {
+	challengeType := m.ChallengeType
+	if challengeType == "" {
+		challengeType = challenge.HTTP01
+	}
+	return challenge.For(challengeType)
+}
+
+// acmeOptions builds the acme.Options for placing an order against
+// this certificate's configured directory, DNS provider and challenge
+// type.
+func (m *Model) acmeOptions() (acme.Options, error) {
+	solver, err := m.dnsSolver()
+	if err != nil {
+		return acme.Options{}, err
+	}
+
+	var dispatcher challenge.Dispatcher
+	if solver == nil {
+		if dispatcher, err = m.challengeDispatcher(); err != nil {
+			return acme.Options{}, err
+		}
+	}
+
+	eabHMACKey, err := m.eabHMACKeyPlaintext()
+	if err != nil {
+		return acme.Options{}, err
+	}
+
+	return acme.Options{
+		DomainNames:  m.DomainNames,
+		DirectoryURL: m.ACMEDirectoryURL,
+		EABKeyID:     m.EABKeyID,
+		EABHMACKey:   eabHMACKey,
+		Solver:       solver,
+		Challenge:    dispatcher,
+	}, nil
+}
+
+// challengeTypeLabel reports the audit-trail label for whichever
+// challenge mechanism this certificate actually used.
+func (m *Model) challengeTypeLabel() string {
+	if m.DNSProvider != "" {
+		return "dns-01"
+	}
+	if m.ChallengeType != "" {
+		return string(m.ChallengeType)
+	}
+	return string(challenge.HTTP01)
+}
+
+// recordChallengeAttempts writes one certificatechallenge entry per
+// domain name, for debugging failed issuances.
+func (m *Model) recordChallengeAttempts(attemptErr error) {
+	challengeType := m.challengeTypeLabel()
+	errMessage := ""
+	if attemptErr != nil {
+		errMessage = attemptErr.Error()
+	}
+
+	for _, domain := range m.DomainNames {
+		entry := certificatechallenge.Model{
+			CertificateID: m.ID,
+			Domain:        domain,
+			Type:          challengeType,
+			Success:       attemptErr == nil,
+			Error:         errMessage,
+		}
+		// nolint: errcheck,gosec
+		entry.Save()
+	}
+}
+
+// applyIssuedCertificate stores the material from a successful
+// issuance/renewal onto the model, ready for Save.
+func (m *Model) applyIssuedCertificate(cert *acme.Certificate) error {
+	m.certificatePEM = string(cert.CertificatePEM)
+	m.issuerCertificatePEM = string(cert.ChainPEM)
+
+	privateKeyPEMEnc, err := crypto.EncryptSecret(cert.PrivateKeyPEM)
+	if err != nil {
+		return err
+	}
+	m.privateKeyPEMEnc = privateKeyPEMEnc
+
+	m.NotAfter = int(cert.NotAfter.Unix())
+	m.RenewalStatus = RenewalStatusNone
+	m.RenewalAttempts = 0
+	m.NextRenewalAttempt = 0
+	return nil
+}
+
+// maxRenewalBackoff caps how long FindDueForRenewal will leave a
+// repeatedly failing certificate alone before trying again.
+const maxRenewalBackoff = 7 * 24 * time.Hour
+
+// renewalBackoff returns how long to wait before retrying after
+// attempts consecutive failures, doubling each time and capped at
+// maxRenewalBackoff so a cert isn't abandoned forever.
+func renewalBackoff(attempts int) time.Duration {
+	backoff := time.Hour << uint(attempts)
+	if backoff <= 0 || backoff > maxRenewalBackoff {
+		return maxRenewalBackoff
+	}
+	return backoff
+}
+
+// FromImport builds a Model for a certificate that was supplied by the
+// user (PEM bundle or PKCS#12) rather than issued via ACME. The caller
+// is still responsible for calling Save.
+func FromImport(userID int, bundle *certimport.Bundle) (Model, error) {
+	privateKeyPEMEnc, err := crypto.EncryptSecret(bundle.PrivateKeyPEM)
+	if err != nil {
+		return Model{}, err
+	}
+
+	return Model{
+		UserID:               userID,
+		Type:                 TypeCustom,
+		DomainNames:          bundle.DomainNames,
+		NotAfter:             int(bundle.NotAfter.Unix()),
+		RenewalStatus:        RenewalStatusNone,
+		certificatePEM:       string(bundle.LeafPEM),
+		issuerCertificatePEM: string(bundle.IssuerPEM),
+		privateKeyPEMEnc:     privateKeyPEMEnc,
+	}, nil
+}
+
+// Request performs the ACME issuance for this certificate and persists
+// the resulting expiry so renewals can be scheduled later.
+func (m *Model) Request() error {
+	opts, err := m.acmeOptions()
+	if err != nil {
+		return err
+	}
+
+	cert, err := acme.Request(opts)
+	m.recordChallengeAttempts(err)
+	if err != nil {
+		return err
+	}
+
+	if err := m.applyIssuedCertificate(cert); err != nil {
+		return err
+	}
+	return m.Save()
+}
+
+// Renew re-issues this certificate ahead of its expiry, reusing the
+// same ACME client wiring as Request.
+func (m *Model) Renew() error {
+	opts, err := m.acmeOptions()
+	if err != nil {
+		return err
+	}
+
+	m.RenewalStatus = RenewalStatusRenewing
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	cert, err := acme.Renew(opts)
+	m.recordChallengeAttempts(err)
+	if err != nil {
+		m.RenewalStatus = RenewalStatusFailed
+		m.RenewalAttempts++
+		m.NextRenewalAttempt = int(time.Now().Add(renewalBackoff(m.RenewalAttempts)).Unix())
+		// nolint: errcheck,gosec
+		m.Save()
+		return err
+	}
+
+	if err := m.applyIssuedCertificate(cert); err != nil {
+		return err
+	}
+	return m.Save()
+}
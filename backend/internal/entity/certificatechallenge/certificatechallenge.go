@@ -0,0 +1,68 @@
+// Package certificatechallenge records every ACME challenge attempt
+// made while issuing or renewing a certificate, as an audit trail for
+// debugging failed issuances.
+package certificatechallenge
+
+import (
+	"time"
+
+	"npm/internal/database"
+)
+
+// Model is a single challenge attempt.
+type Model struct {
+	ID            int    `json:"id"`
+	CreatedOn     int    `json:"created_on"`
+	CertificateID int    `json:"certificate_id"`
+	Domain        string `json:"domain"`
+	Type          string `json:"type"`
+	Success       bool   `json:"success"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Save records this attempt.
+func (m *Model) Save() error {
+	if m.CreatedOn == 0 {
+		m.CreatedOn = int(time.Now().Unix())
+	}
+
+	result, err := database.GetDB().Exec(`
+		INSERT INTO certificate_challenge (created_on, certificate_id, domain, type, success, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, m.CreatedOn, m.CertificateID, m.Domain, m.Type, m.Success, m.Error)
+	if err != nil {
+		return err
+	}
+
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(lastID)
+	return nil
+}
+
+// ListByCertificate returns every recorded attempt for a certificate,
+// most recent first.
+func ListByCertificate(certificateID int) ([]Model, error) {
+	rows, err := database.GetDB().Query(`
+		SELECT id, created_on, certificate_id, domain, type, success, error
+		FROM certificate_challenge
+		WHERE certificate_id = ?
+		ORDER BY created_on DESC
+	`, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []Model
+	for rows.Next() {
+		var m Model
+		if err := rows.Scan(&m.ID, &m.CreatedOn, &m.CertificateID, &m.Domain, &m.Type, &m.Success, &m.Error); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, m)
+	}
+	return attempts, rows.Err()
+}
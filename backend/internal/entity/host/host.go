@@ -0,0 +1,28 @@
+// Package host is the entity package for proxy/redirection/stream
+// hosts that reference a certificate.
+package host
+
+import (
+	"npm/internal/database"
+)
+
+// GetCertificateUseCount returns how many hosts currently reference
+// the given certificate, so it can't be deleted while in use.
+func GetCertificateUseCount(certificateID int) int {
+	var count int
+	row := database.GetDB().QueryRow(`
+		SELECT COUNT(*) FROM host WHERE certificate_id = ? AND is_deleted = 0
+	`, certificateID)
+	// nolint: errcheck,gosec
+	row.Scan(&count)
+	return count
+}
+
+// RemoveCertificateReferences clears certificate_id on every host that
+// references it, so a revoked certificate stops being served.
+func RemoveCertificateReferences(certificateID int) error {
+	_, err := database.GetDB().Exec(`
+		UPDATE host SET certificate_id = 0 WHERE certificate_id = ?
+	`, certificateID)
+	return err
+}
@@ -0,0 +1,33 @@
+// Package jobqueue provides a simple in-process queue for background
+// work such as certificate requests and renewals.
+package jobqueue
+
+import (
+	"npm/internal/logger"
+)
+
+// Job is a unit of background work.
+type Job struct {
+	Name   string
+	Action func() error
+}
+
+var queue = make(chan Job, 100)
+
+func init() {
+	go worker()
+}
+
+// AddJob enqueues a job for asynchronous execution.
+func AddJob(job Job) error {
+	queue <- job
+	return nil
+}
+
+func worker() {
+	for job := range queue {
+		if err := job.Action(); err != nil {
+			logger.Error(job.Name+"Error", err)
+		}
+	}
+}
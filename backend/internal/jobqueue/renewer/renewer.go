@@ -0,0 +1,104 @@
+// Package renewer periodically scans certificates that are approaching
+// expiry and dispatches renewal jobs for them.
+package renewer
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"npm/internal/entity/certificate"
+	"npm/internal/jobqueue"
+	"npm/internal/logger"
+)
+
+const (
+	defaultWindowDays    = 30
+	defaultScanInterval  = time.Hour
+	defaultMaxConcurrent = 5
+)
+
+// renewSlots bounds how many renewal jobs run at once, independently of
+// the shared job queue's own concurrency (which also serves certificate
+// issuance and nginx-reload jobs). It's sized lazily on first use so
+// RENEWAL_MAX_CONCURRENT can be read at startup without forcing callers
+// to order themselves around it.
+var renewSlots chan struct{}
+
+// Start launches the renewal scanner in the background. It scans once
+// on startup and then on a fixed interval for as long as the process
+// runs. RENEWAL_MAX_CONCURRENT bounds both how many certificates are
+// pulled per scan and how many renewal jobs actually run at once.
+func Start() {
+	renewSlots = make(chan struct{}, maxConcurrent())
+	go run()
+}
+
+func run() {
+	for {
+		scan()
+		time.Sleep(scanInterval())
+	}
+}
+
+// scan finds certificates within the renewal window and enqueues a
+// RenewCertificate job for each, up to maxConcurrent at a time.
+func scan() {
+	cutoff := int(time.Now().Add(renewalWindow()).Unix())
+
+	certs, err := certificate.FindDueForRenewal(cutoff, maxConcurrent())
+	if err != nil {
+		logger.Error("RenewalScanError", err)
+		return
+	}
+
+	for _, cert := range certs {
+		cert := cert
+		err := jobqueue.AddJob(jobqueue.Job{
+			Name:   "RenewCertificate",
+			Action: renewWithinLimit(cert),
+		})
+		if err != nil {
+			logger.Error("RenewalEnqueueError", err)
+		}
+	}
+}
+
+// renewWithinLimit wraps cert.Renew so that, no matter how many renewal
+// jobs the shared job queue happens to run in parallel, at most
+// maxConcurrent of them are actually renewing at once.
+func renewWithinLimit(cert certificate.Model) func() error {
+	return func() error {
+		renewSlots <- struct{}{}
+		defer func() { <-renewSlots }()
+		return cert.Renew()
+	}
+}
+
+func renewalWindow() time.Duration {
+	days := defaultWindowDays
+	if v := os.Getenv("RENEWAL_WINDOW_DAYS"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func scanInterval() time.Duration {
+	if v := os.Getenv("RENEWAL_SCAN_INTERVAL_MINUTES"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			return time.Duration(parsed) * time.Minute
+		}
+	}
+	return defaultScanInterval
+}
+
+func maxConcurrent() int {
+	if v := os.Getenv("RENEWAL_MAX_CONCURRENT"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			return parsed
+		}
+	}
+	return defaultMaxConcurrent
+}
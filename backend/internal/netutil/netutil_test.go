@@ -0,0 +1,73 @@
+package netutil
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPubliclyRoutable(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata endpoint
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fe80::1", false},
+	}
+
+	for _, c := range cases {
+		ip := net.ParseIP(c.ip)
+		if ip == nil {
+			t.Fatalf("invalid test IP %q", c.ip)
+		}
+		if got := isPubliclyRoutable(ip); got != c.want {
+			t.Errorf("isPubliclyRoutable(%s) = %v, want %v", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestValidateURLRejectsNonRoutableAndBadSchemes(t *testing.T) {
+	cases := []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:8080/",
+		"http://10.0.0.5/",
+		"ftp://example.com/",
+		"not a url",
+	}
+	for _, rawURL := range cases {
+		if err := validateURL(rawURL); err == nil {
+			t.Errorf("validateURL(%q) = nil, want error", rawURL)
+		}
+	}
+
+	if err := validateURL("https://example.com/directory"); err != nil {
+		t.Errorf("validateURL(public https URL) = %v, want nil", err)
+	}
+}
+
+func TestCheckRedirectRefusesNonRoutableAddress(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data/", nil)
+
+	if err := checkRedirect(req, nil); err == nil {
+		t.Fatal("checkRedirect allowed a redirect to a non-routable address")
+	}
+}
+
+func TestCheckRedirectCapsHopCount(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	via := make([]*http.Request, maxRedirects)
+
+	if err := checkRedirect(req, via); err == nil {
+		t.Fatal("checkRedirect allowed exceeding maxRedirects")
+	}
+}
@@ -0,0 +1,10 @@
+// Package nginx manages the generated nginx configuration and reloads
+// the running instance when it changes.
+package nginx
+
+// Reload regenerates config for all hosts and signals nginx to reload.
+func Reload() error {
+	// TODO: regenerate vhost configs and send SIGHUP to the nginx
+	// master process (or reload via its control socket in Docker).
+	return nil
+}